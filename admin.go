@@ -0,0 +1,25 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// adminReload handles POST /admin/reload: it re-reads hostFile and
+// reports which hosts were added, removed, or failed validation. Only
+// the affected hosts' probe goroutines are restarted; see Runner.Reload.
+func adminReload(w http.ResponseWriter, r *http.Request) {
+	logreq(r)
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	result, err := runner.Reload(*hostFile)
+	if err != nil {
+		log.Println("adminReload:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,222 @@
+// Package alerts implements pingmeplz's alerting subsystem: a tiny
+// per-host state machine (OK, Pending, Firing, Resolved) driven by the
+// probe samples Runner already collects, plus a set of pluggable
+// Notifiers to deliver the resulting events. Runner stays simple by
+// handing Check a short window of recent samples on every poll tick;
+// all thresholding, state tracking and notification backoff live here.
+package alerts
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// State is a host's position in the alerting state machine.
+type State int
+
+const (
+	OK State = iota
+	Pending
+	Firing
+	Resolved
+)
+
+func (s State) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Pending:
+		return "Pending"
+	case Firing:
+		return "Firing"
+	case Resolved:
+		return "Resolved"
+	}
+	return "Unknown"
+}
+
+// Sample is one probe result, as read back from the host's Store.
+type Sample struct {
+	Time    time.Time
+	Latency time.Duration
+	Err     error
+}
+
+// Event is handed to every configured Notifier on a state transition.
+type Event struct {
+	Host    string
+	State   State
+	Err     error
+	Latency time.Duration
+	Time    time.Time
+}
+
+// Notifier delivers an Event to some external system.
+type Notifier interface {
+	Notify(Event) error
+}
+
+// Rule configures alert triggers and routing for a single host. It's
+// meant to be embedded directly in a host's JSON config entry.
+type Rule struct {
+	// FailThreshold is the number of consecutive failed probes required
+	// before the alert fires. Zero disables failure-based alerting.
+	FailThreshold int `json:"failThreshold,omitempty"`
+
+	// LatencyThreshold/LatencyFor fire when the latency of the last
+	// LatencyFor consecutive successful probes all exceed the threshold.
+	LatencyThreshold time.Duration `json:"latencyThreshold,omitempty"`
+	LatencyFor       int           `json:"latencyFor,omitempty"`
+
+	// Backoff is the minimum time between repeat notifications while a
+	// host stays in the Firing state. Defaults to 15 minutes.
+	Backoff time.Duration `json:"backoff,omitempty"`
+
+	Email   string `json:"email,omitempty"`
+	Webhook string `json:"webhook,omitempty"`
+	Slack   string `json:"slack,omitempty"`
+}
+
+// Window reports how many trailing samples Check needs to evaluate r.
+func (r Rule) Window() int {
+	n := r.FailThreshold
+	if r.LatencyFor > n {
+		n = r.LatencyFor
+	}
+	if n == 0 {
+		n = 1
+	}
+	return n
+}
+
+// Notifiers builds the Notifiers configured on r.
+func (r Rule) Notifiers() []Notifier {
+	var ns []Notifier
+	if r.Email != "" {
+		ns = append(ns, &EmailNotifier{To: r.Email})
+	}
+	if r.Webhook != "" {
+		ns = append(ns, &WebhookNotifier{URL: r.Webhook})
+	}
+	if r.Slack != "" {
+		ns = append(ns, &SlackNotifier{WebhookURL: r.Slack})
+	}
+	return ns
+}
+
+type hostState struct {
+	state      State
+	lastNotify time.Time
+}
+
+// Manager tracks per-host alerting state and dispatches Notifiers.
+// A single Manager should be shared across every host Runner monitors.
+type Manager struct {
+	mu     sync.Mutex
+	states map[string]*hostState
+}
+
+// NewManager returns an empty Manager ready to use.
+func NewManager() *Manager {
+	return &Manager{states: make(map[string]*hostState)}
+}
+
+// Check evaluates samples (oldest first, at least rule.Window() long)
+// against rule's triggers, advances host's state machine and, on a
+// transition into Firing, a repeat while still Firing past rule.Backoff,
+// or a transition into Resolved, notifies every Notifier configured on
+// rule.
+func (m *Manager) Check(host string, rule Rule, samples []Sample) {
+	notifiers := rule.Notifiers()
+	if len(notifiers) == 0 || len(samples) == 0 {
+		return
+	}
+
+	consecFail := consecutiveFailures(samples)
+	consecSlow := 0
+	if rule.LatencyThreshold > 0 {
+		consecSlow = consecutiveSlow(samples, rule.LatencyThreshold)
+	}
+
+	bad := (rule.FailThreshold > 0 && consecFail >= rule.FailThreshold) ||
+		(rule.LatencyThreshold > 0 && consecSlow >= rule.LatencyFor)
+	degraded := (rule.FailThreshold > 0 && consecFail > 0) ||
+		(rule.LatencyThreshold > 0 && consecSlow > 0)
+
+	backoff := rule.Backoff
+	if backoff == 0 {
+		backoff = 15 * time.Minute
+	}
+
+	m.mu.Lock()
+	hs, ok := m.states[host]
+	if !ok {
+		hs = &hostState{state: OK}
+		m.states[host] = hs
+	}
+	now := time.Now()
+
+	var ev *Event
+	switch {
+	case bad && hs.state != Firing:
+		hs.state = Firing
+		hs.lastNotify = now
+		ev = newEvent(host, Firing, samples, now)
+	case bad && hs.state == Firing:
+		if now.Sub(hs.lastNotify) >= backoff {
+			hs.lastNotify = now
+			ev = newEvent(host, Firing, samples, now)
+		}
+	case !bad && hs.state == Firing:
+		hs.state = Resolved
+		ev = newEvent(host, Resolved, samples, now)
+	case !bad && degraded:
+		hs.state = Pending
+	case !bad:
+		hs.state = OK
+	}
+	m.mu.Unlock()
+
+	if ev == nil {
+		return
+	}
+	for _, n := range notifiers {
+		if err := n.Notify(*ev); err != nil {
+			log.Printf("alerts: notify %v for %v failed: %v", host, ev.State, err)
+		}
+	}
+}
+
+func newEvent(host string, state State, samples []Sample, now time.Time) *Event {
+	last := samples[len(samples)-1]
+	return &Event{
+		Host:    host,
+		State:   state,
+		Err:     last.Err,
+		Latency: last.Latency,
+		Time:    now,
+	}
+}
+
+func consecutiveFailures(samples []Sample) int {
+	n := 0
+	for i := len(samples) - 1; i >= 0; i-- {
+		if samples[i].Err == nil {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+func consecutiveSlow(samples []Sample, threshold time.Duration) int {
+	n := 0
+	for i := len(samples) - 1; i >= 0; i-- {
+		if samples[i].Err != nil || samples[i].Latency <= threshold {
+			break
+		}
+		n++
+	}
+	return n
+}
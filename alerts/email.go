@@ -0,0 +1,31 @@
+package alerts
+
+import (
+	"fmt"
+	"net/smtp"
+)
+
+// EmailNotifier delivers alert notifications over SMTP to a single
+// address, using Host.Email from the host's config.
+type EmailNotifier struct {
+	To   string
+	From string
+	Addr string // SMTP server address, e.g. "localhost:25".
+	Auth smtp.Auth
+}
+
+func (e *EmailNotifier) Notify(ev Event) error {
+	addr := e.Addr
+	if addr == "" {
+		addr = "localhost:25"
+	}
+	from := e.From
+	if from == "" {
+		from = "pingmeplz@localhost"
+	}
+	subject := fmt.Sprintf("[pingmeplz] %v is %v", ev.Host, ev.State)
+	body := fmt.Sprintf("Host: %v\nState: %v\nLatency: %v\nError: %v\nTime: %v\n",
+		ev.Host, ev.State, ev.Latency, ev.Err, ev.Time)
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s", from, e.To, subject, body)
+	return smtp.SendMail(addr, e.Auth, from, []string{e.To}, []byte(msg))
+}
@@ -0,0 +1,39 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// SlackNotifier posts an alert notification to a Slack incoming webhook.
+type SlackNotifier struct {
+	WebhookURL string
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+func (s *SlackNotifier) Notify(ev Event) error {
+	text := fmt.Sprintf("*%v* is *%v*", ev.Host, ev.State)
+	if ev.Err != nil {
+		text += fmt.Sprintf(" (%v)", ev.Err)
+	} else {
+		text += fmt.Sprintf(" (%v)", ev.Latency)
+	}
+	body, err := json.Marshal(slackPayload{Text: text})
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(s.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned %v", resp.Status)
+	}
+	return nil
+}
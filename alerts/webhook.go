@@ -0,0 +1,48 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier POSTs a JSON payload describing the event to an
+// arbitrary HTTP endpoint.
+type WebhookNotifier struct {
+	URL string
+}
+
+type webhookPayload struct {
+	Host    string    `json:"host"`
+	State   string    `json:"state"`
+	Error   string    `json:"error,omitempty"`
+	Latency float64   `json:"latency_seconds"`
+	Time    time.Time `json:"time"`
+}
+
+func (w *WebhookNotifier) Notify(ev Event) error {
+	p := webhookPayload{
+		Host:    ev.Host,
+		State:   ev.State.String(),
+		Latency: ev.Latency.Seconds(),
+		Time:    ev.Time,
+	}
+	if ev.Err != nil {
+		p.Error = ev.Err.Error()
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %v returned %v", w.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+)
+
+// RegisterResponse answers POST /api/v1/worker/register.
+type RegisterResponse struct {
+	WorkerID WorkerID `json:"workerId"`
+}
+
+// PollResponse answers GET /api/v1/worker/poll.
+type PollResponse struct {
+	Assignments []Assignment `json:"assignments"`
+}
+
+// ReportRequest is POSTed to /api/v1/worker/report with one probe
+// result. Signature is Sign(token, r.Payload()); see Payload, Sign and
+// Coordinator.Verify.
+type ReportRequest struct {
+	WorkerID   WorkerID  `json:"workerId"`
+	Host       string    `json:"host"`
+	Time       time.Time `json:"time"`
+	LatencyNs  int64     `json:"latencyNs"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Err        string    `json:"err,omitempty"`
+	Signature  string    `json:"signature"`
+}
+
+// Payload returns the canonical bytes a ReportRequest signs: every
+// field except Signature itself.
+func (r ReportRequest) Payload() []byte {
+	return []byte(fmt.Sprintf("%s|%s|%d|%d|%d|%s",
+		r.WorkerID, r.Host, r.Time.UnixNano(), r.LatencyNs, r.StatusCode, r.Err))
+}
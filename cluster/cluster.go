@@ -0,0 +1,173 @@
+// Package cluster implements pingmeplz's collaborative mode: a
+// coordinator shards the monitored hostnames across registered worker
+// agents via consistent hashing, expires workers that stop polling, and
+// reassigns their hosts to the remaining ones.
+package cluster
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WorkerID identifies a registered worker agent.
+type WorkerID string
+
+// Assignment is one host a worker has been asked to probe.
+type Assignment struct {
+	Host string `json:"host"`
+}
+
+type workerState struct {
+	lastSeen time.Time
+}
+
+// Coordinator tracks registered workers and shards hostnames across
+// them. The zero value is not usable; construct with NewCoordinator.
+type Coordinator struct {
+	token          string
+	heartbeatEvery time.Duration
+
+	mu      sync.Mutex
+	workers map[WorkerID]*workerState
+	hosts   []string
+}
+
+// NewCoordinator returns a Coordinator that authenticates worker
+// reports against token and expires a worker once it hasn't polled for
+// heartbeatEvery.
+func NewCoordinator(token string, heartbeatEvery time.Duration) *Coordinator {
+	return &Coordinator{
+		token:          token,
+		heartbeatEvery: heartbeatEvery,
+		workers:        make(map[WorkerID]*workerState),
+	}
+}
+
+// SetHosts replaces the set of hostnames sharded across workers. Runner
+// calls this whenever its monitored host set changes.
+func (c *Coordinator) SetHosts(hosts []string) {
+	sorted := append([]string(nil), hosts...)
+	sort.Strings(sorted)
+	c.mu.Lock()
+	c.hosts = sorted
+	c.mu.Unlock()
+}
+
+// Register enrolls a new worker and returns its ID.
+func (c *Coordinator) Register() WorkerID {
+	id := WorkerID(fmt.Sprintf("worker-%d", time.Now().UnixNano()))
+	c.mu.Lock()
+	c.workers[id] = &workerState{lastSeen: time.Now()}
+	c.mu.Unlock()
+	return id
+}
+
+// Assignments returns the hosts currently shard-assigned to id. It also
+// refreshes id's heartbeat and expires any worker that has gone quiet
+// for longer than heartbeatEvery, so their hosts are reassigned within
+// one poll cycle.
+func (c *Coordinator) Assignments(id WorkerID) ([]Assignment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w, ok := c.workers[id]
+	if !ok {
+		return nil, fmt.Errorf("cluster: unknown worker %v", id)
+	}
+	w.lastSeen = time.Now()
+	c.expireLocked()
+
+	if _, ok := c.workers[id]; !ok {
+		// id expired in the same instant it was about to be refreshed;
+		// treat it as unknown rather than silently handing out work.
+		return nil, fmt.Errorf("cluster: unknown worker %v", id)
+	}
+
+	live := c.liveWorkerIDsLocked()
+	if len(live) == 0 {
+		return nil, nil
+	}
+	var out []Assignment
+	for _, host := range c.hosts {
+		if shardFor(host, live) == id {
+			out = append(out, Assignment{Host: host})
+		}
+	}
+	return out, nil
+}
+
+// WorkerIDs returns the IDs of every currently live worker, sorted.
+func (c *Coordinator) WorkerIDs() []WorkerID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expireLocked()
+	return c.liveWorkerIDsLocked()
+}
+
+func (c *Coordinator) expireLocked() {
+	cutoff := time.Now().Add(-c.heartbeatEvery)
+	for id, w := range c.workers {
+		if w.lastSeen.Before(cutoff) {
+			delete(c.workers, id)
+		}
+	}
+}
+
+func (c *Coordinator) liveWorkerIDsLocked() []WorkerID {
+	ids := make([]WorkerID, 0, len(c.workers))
+	for id := range c.workers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// shardFor assigns host to exactly one of ids via rendezvous (highest
+// random weight) hashing: each worker's score for host is an
+// independent hash of the (host, worker) pair, and host goes to
+// whichever worker scores highest. Unlike a modulo hash over len(ids),
+// this means a worker joining or leaving only reshuffles the hosts that
+// score highest for that worker -- every other host's assignment is
+// unaffected.
+func shardFor(host string, ids []WorkerID) WorkerID {
+	var best WorkerID
+	var bestScore uint32
+	for i, id := range ids {
+		h := fnv.New32a()
+		h.Write([]byte(host))
+		h.Write([]byte{'#'})
+		h.Write([]byte(id))
+		score := h.Sum32()
+		if i == 0 || score > bestScore {
+			best, bestScore = id, score
+		}
+	}
+	return best
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body under the
+// coordinator's shared token, for a worker to attach to its report.
+func Sign(token string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid signature of body under
+// the coordinator's shared token.
+func (c *Coordinator) Verify(body []byte, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(c.token, body)))
+}
+
+// CheckToken reports whether token is the coordinator's shared secret.
+// Register and Assignments have no signed payload to Verify against, so
+// callers authenticate those with the bare token instead.
+func (c *Coordinator) CheckToken(token string) bool {
+	return hmac.Equal([]byte(token), []byte(c.token))
+}
@@ -1,11 +1,23 @@
 package main
 
 import (
+	"encoding/json"
 	"html/template"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nictuku/pingmeplz/cluster"
 )
 
+type historyData struct {
+	Host        *Host
+	SeriesNames []string
+	RowsJSON    template.JS
+}
+
 func history(w http.ResponseWriter, r *http.Request) {
 	logreq(r)
 	v := "host"
@@ -15,12 +27,85 @@ func history(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, v+" not specified or invalid", http.StatusBadRequest)
 		return
 	}
-	if err := historyTmpl.Execute(w, h); err != nil {
+
+	from := parseTimeParam(r, "from", time.Now().Add(-7*24*time.Hour))
+	to := parseTimeParam(r, "to", time.Now())
+
+	// "local" is the coordinator's own series; every other live worker
+	// contributes its own vantage-point series for the same host.
+	names := []string{"local"}
+	for _, id := range runner.coordinator.WorkerIDs() {
+		names = append(names, string(id))
+	}
+
+	rows, err := historyRows(h, names, from, to)
+	if err != nil {
+		http.Error(w, "oops", http.StatusInternalServerError)
+		log.Println("history Range error:", err)
+		return
+	}
+
+	rowsJSON, err := json.Marshal(rows)
+	if err != nil {
+		http.Error(w, "oops", http.StatusInternalServerError)
+		log.Println("history marshal error:", err)
+		return
+	}
+
+	data := historyData{Host: h, SeriesNames: names, RowsJSON: template.JS(rowsJSON)}
+	if err := historyTmpl.Execute(w, data); err != nil {
 		http.Error(w, "oops", http.StatusInternalServerError)
 		log.Println("history error:", err)
 	}
 }
 
+// historyRows reads each named series (names[0] is always the host's
+// own local series, read back under its primary probe's key exactly as
+// Status() does; the rest are "<workerId>" vantage points) and merges
+// them, oldest first, into sparse rows of
+// [unixMillis, series0, series1, ...] with nil where a series has no
+// sample at that timestamp. Failed probes are omitted, matching the
+// original chart's behavior of only plotting successful latencies.
+func historyRows(h *Host, names []string, from, to time.Time) ([][]interface{}, error) {
+	localKey := sampleKey(h.Host, h.probeSpecs()[0])
+	var rows [][]interface{}
+	for i, name := range names {
+		key := localKey
+		if name != "local" {
+			key = vantageKey(h.Host, cluster.WorkerID(name))
+		}
+		samples, err := runner.Store.Range(key, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range samples {
+			if s.Err != nil {
+				continue
+			}
+			row := make([]interface{}, len(names)+1)
+			row[0] = s.Time.UnixNano() / int64(time.Millisecond)
+			row[i+1] = s.Latency.Seconds()
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][0].(int64) < rows[j][0].(int64) })
+	return rows, nil
+}
+
+// parseTimeParam reads a unix-seconds timestamp from the named query
+// parameter, falling back to def if it's missing or malformed.
+func parseTimeParam(r *http.Request, name string, def time.Time) time.Time {
+	v := r.FormValue(name)
+	if v == "" {
+		return def
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return def
+	}
+	return time.Unix(sec, 0)
+}
+
 // Template based on examples from:
 // https://code.google.com/apis/ajax/playground/?type=visualization#annotated_time_line
 //
@@ -31,7 +116,7 @@ var historyTmpl = template.Must(template.New("").Parse(`
 <!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">
 <html xmlns="http://www.w3.org/1999/xhtml">
 <head>
-  <title> {{ .Host }} </title>
+  <title> {{ .Host.Host }} </title>
   <meta http-equiv="content-type" content="text/html; charset=utf-8" />
   <script type="text/javascript" src="http://www.google.com/jsapi"></script>
   <script type="text/javascript">
@@ -39,26 +124,30 @@ var historyTmpl = template.Must(template.New("").Parse(`
     function drawVisualization() {
       var data = new google.visualization.DataTable();
       data.addColumn('datetime', 'Date');
-      data.addColumn('number', '{{ .Host }}');
-      data.addRows([
-        {{ range $index, $latency := .Latency }}
-          {{ if $latency }}[new Date({{ index $.CollectionTime $index }}), {{ $latency.Seconds }} ],{{ end }}
-        {{ end }}
-      ]);
-    
+      {{ range .SeriesNames }}data.addColumn('number', {{ . }});
+      {{ end }}
+
+      var raw = {{ .RowsJSON }};
+      var rows = raw.map(function(row) {
+        return [new Date(row[0])].concat(row.slice(1));
+      });
+      data.addRows(rows);
+
       var options = {
-        title: 'HTTP GET / overall latency in seconds.',
+        title: 'Probe latency in seconds, one line per vantage point.',
         vAxis: {baseline: 0},
       };
 
       var formatter = new google.visualization.NumberFormat({fractionDigits: 3});
-      formatter.format(data, 1);
+      for (var i = 1; i < data.getNumberOfColumns(); i++) {
+        formatter.format(data, i);
+      }
 
       var chart = new google.visualization.LineChart(
           document.getElementById('visualization'));
       chart.draw(data, options);
     }
-    
+
     google.setOnLoadCallback(drawVisualization);
   </script>
 </head>
@@ -20,16 +20,25 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"reflect"
+	"sort"
 	"sync"
+	"syscall"
 	"time"
+
+	"github.com/nictuku/pingmeplz/alerts"
+	"github.com/nictuku/pingmeplz/cluster"
+	"github.com/nictuku/pingmeplz/probe"
+	"github.com/nictuku/pingmeplz/store"
 )
 
 var (
@@ -37,58 +46,92 @@ var (
 	pollInterval = flag.Duration("poll", time.Second*10, "file poll interval")
 	readTimeout  = flag.Duration("timeout", time.Second*10, "response read timeout")
 	maxHosts     = flag.Int("maxHosts", 100, "Maximum number of hosts we should monitor")
-)
+	storeKind    = flag.String("store", "memory", "sample storage backend: \"memory\" or \"sqlite\"")
+	sqlitePath   = flag.String("sqlitePath", "pingmeplz.db", "path to the sqlite database, when -store=sqlite")
+	retention    = flag.Duration("retention", 0, "delete samples older than this, when -store=sqlite (0 disables pruning)")
 
-const (
-	// How many latency data points to keep for each host. 
-	// This is the primary driver of memory usage.
-	bufSize = 10080 // 7d worth of 1m frequency collections.
+	worker          = flag.String("worker", "", "run as a collaborative worker agent against this coordinator URL, instead of serving the web UI")
+	workerToken     = flag.String("workerToken", "", "shared token used to authenticate worker reports")
+	workerHeartbeat = flag.Duration("workerHeartbeat", time.Minute, "how long a worker can go quiet before its hosts are reassigned")
 )
 
 var runner *Runner
 
 func main() {
 	flag.Parse()
+
+	if *worker != "" {
+		runWorker(*worker, *workerToken, *pollInterval)
+		return
+	}
+
 	runner = StartRunner(*hostFile, *pollInterval)
 
 	http.HandleFunc("/", welcome)
 	http.HandleFunc("/newhost", newhost)
 	http.HandleFunc("/history", history)
+	http.HandleFunc("/metrics", metricsHandler)
+	http.HandleFunc("/api/v1/worker/register", workerRegister)
+	http.HandleFunc("/api/v1/worker/poll", workerPoll)
+	http.HandleFunc("/api/v1/worker/report", workerReport)
+	http.HandleFunc("/admin/reload", adminReload)
 	http.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.Dir("assets"))))
 
 	log.Panic(http.ListenAndServe(":8080", nil))
 }
 
 type Runner struct {
-	sync.Mutex // Protects errors during concurrent Ping
-	last       time.Time
-	Hosts      map[string]*Host
+	sync.Mutex // Protects Hosts and hostCancel during concurrent reads/writes.
+	last        time.Time
+	Hosts       map[string]*Host
+	hostCancel  map[string]context.CancelFunc // stops a host's probe goroutines
+	Store       store.Store
+	alertMgr    *alerts.Manager
+	coordinator *cluster.Coordinator
 }
 
 type Host struct {
 	Host  string
 	Email string
 
-	// Protects pos, Latency and Error
-	sync.Mutex `json:"-"`
-	pos        int `json:"-"` // 0..9 
+	// Alerts configures when and how to notify about this host. It's
+	// left zero-valued (no thresholds set) if the host has no alerting
+	// configured, which alerts.Manager treats as "never fire".
+	Alerts alerts.Rule `json:"alerts,omitempty"`
 
-	// TODO: Optimize. Wasting too much memory.
-	Latency        [bufSize]time.Duration `json:"-"`
-	Error          [bufSize]error         `json:"-"`
-	CollectionTime [bufSize]time.Time     `json:"-"`
+	// Probes are the checks run against this host. An empty list falls
+	// back to a single plain HTTP probe against "/", matching
+	// pingmeplz's original behavior.
+	Probes []probe.ProbeSpec `json:"probes,omitempty"`
 }
 
-func (h *Host) Status() string {
-	h.Lock()
-	defer h.Unlock()
-	e := h.Error[h.pos]
-	if e != nil {
-		return "Error: " + e.Error()
+// probeSpecs returns the probes configured on h, falling back to a
+// single plain HTTP probe against "/" when h.Probes is empty, matching
+// pingmeplz's original behavior. scheduleHost, Status and the /metrics
+// and /history handlers all need this same fallback to agree on what a
+// host with no Probes configured is actually running.
+func (h *Host) probeSpecs() []probe.ProbeSpec {
+	if len(h.Probes) == 0 {
+		return []probe.ProbeSpec{{Type: "http", Path: "/"}}
 	}
-	fmt.Printf("%v %v\n", h.Host, h.Latency)
-	return fmt.Sprintf("%dms", h.Latency[h.pos]/time.Millisecond)
+	return h.Probes
+}
 
+// Status renders the most recent probe result for h's primary probe —
+// the first one configured, or the default root HTTP probe when h has
+// none — as read from the Runner's Store. A host monitored only by a
+// non-default probe (e.g. TCP or ICMP) is read back under that probe's
+// own key instead of always looking up h.Host.
+func (h *Host) Status() string {
+	specs := h.probeSpecs()
+	s, err := runner.Store.Latest(sampleKey(h.Host, specs[0]))
+	if err != nil {
+		return "no data"
+	}
+	if s.Err != nil {
+		return "Error: " + s.Err.Error()
+	}
+	return fmt.Sprintf("%dms", s.Latency/time.Millisecond)
 }
 
 func getWithTimeout(u string, timeout time.Duration) (*http.Response, error) {
@@ -108,56 +151,133 @@ func getWithTimeout(u string, timeout time.Duration) (*http.Response, error) {
 	return c.Get(u)
 }
 
-func (r *Runner) Ping(h *Host) error {
-	u := fmt.Sprintf("http://%s/", h.Host)
-	start := time.Now()
-	resp, err := getWithTimeout(u, *readTimeout)
-	duration := time.Since(start)
+// sampleKey is the Store key a given probe's samples are recorded
+// under. Plain HTTP probes against "/" keep the host's own name, so
+// hosts with no Probes configured (the common case) read back exactly
+// as before; any other probe gets its own series, qualified by whatever
+// distinguishes it from a sibling probe of the same Type (port, path or
+// resolver), so two probes of the same Type against the same host don't
+// clobber each other's history.
+func sampleKey(host string, spec probe.ProbeSpec) string {
+	typ := spec.Type
+	if typ == "" {
+		typ = "http"
+	}
+	if typ == "http" && (spec.Path == "" || spec.Path == "/") {
+		return host
+	}
+	switch typ {
+	case "http":
+		return fmt.Sprintf("%s#http#%s", host, spec.Path)
+	case "tcp", "tls":
+		return fmt.Sprintf("%s#%s#%d", host, typ, spec.Port)
+	case "dns":
+		return fmt.Sprintf("%s#dns#%s", host, spec.Resolver)
+	default:
+		return fmt.Sprintf("%s#%s", host, typ)
+	}
+}
+
+// probeLabel is a short string identifying spec among a host's other
+// probes, for use as a label in /metrics and /history output. It
+// mirrors the qualifiers sampleKey adds, without the host prefix.
+func probeLabel(spec probe.ProbeSpec) string {
+	typ := spec.Type
+	if typ == "" {
+		typ = "http"
+	}
+	switch typ {
+	case "http":
+		path := spec.Path
+		if path == "" {
+			path = "/"
+		}
+		return fmt.Sprintf("http:%s", path)
+	case "tcp", "tls":
+		return fmt.Sprintf("%s:%d", typ, spec.Port)
+	case "dns":
+		return fmt.Sprintf("dns:%s", spec.Resolver)
+	default:
+		return typ
+	}
+}
+
+// RunProbe builds and runs the probe spec describes against h, records
+// the result in the Store and feeds the alert manager.
+func (r *Runner) RunProbe(h *Host, spec probe.ProbeSpec) error {
+	p, err := spec.Build(h.Host)
 	if err != nil {
-		log.Printf("%v FAIL after %v", h.Host, duration)
-		return r.Fail(h, err)
-	}
-	resp.Body.Close()
-	if resp.StatusCode != 200 {
-		log.Printf("%v ERROR after %v", h.Host, duration)
-		return r.Fail(h, errors.New(resp.Status))
-	}
-	log.Printf("%v OK after %v", h.Host, duration)
-	return r.OK(h, duration)
-}
-
-func (r *Runner) OK(h *Host, duration time.Duration) error {
-	h.Lock()
-	h.pos = (h.pos + 1) % bufSize
-	h.Latency[h.pos] = duration
-	h.CollectionTime[h.pos] = time.Now()
-	log.Printf("latency for %d %v", h.pos, duration)
-	h.Error[h.pos] = nil
-	h.Unlock()
+		return fmt.Errorf("RunProbe: %v", err)
+	}
+
+	timeout := spec.Timeout
+	if timeout == 0 {
+		timeout = *readTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	key := sampleKey(h.Host, spec)
+	result, runErr := p.Run(ctx)
+	sample := store.Sample{
+		Time:       time.Now(),
+		Latency:    result.Latency,
+		StatusCode: result.StatusCode,
+		Err:        runErr,
+	}
+	if runErr != nil {
+		log.Printf("%v FAIL after %v: %v", key, result.Latency, runErr)
+	} else {
+		log.Printf("%v OK after %v", key, result.Latency)
+	}
+
+	if err := r.Store.Append(key, sample); err != nil {
+		return fmt.Errorf("RunProbe Store Append: %v", err)
+	}
+	r.checkAlerts(h, key)
 	return nil
 }
 
-func (r *Runner) Fail(h *Host, getErr error) error {
-	h.Lock()
-	h.pos = (h.pos + 1) % bufSize
-	h.CollectionTime[h.pos] = time.Now()
-	h.Error[h.pos] = getErr
-	h.Unlock()
-	return nil
+// checkAlerts reads back the trailing window of samples h.Alerts needs
+// for the series recorded under key, and feeds them to the alert
+// manager. Email alerts go to h.Email unless h.Alerts sets its own.
+func (r *Runner) checkAlerts(h *Host, key string) {
+	rule := h.Alerts
+	if rule.Email == "" {
+		rule.Email = h.Email
+	}
+	samples, err := r.Store.LastN(key, rule.Window())
+	if err != nil {
+		log.Println("checkAlerts LastN:", err)
+		return
+	}
+	alertSamples := make([]alerts.Sample, len(samples))
+	for i, s := range samples {
+		alertSamples[i] = alerts.Sample{Time: s.Time, Latency: s.Latency, Err: s.Err}
+	}
+	r.alertMgr.Check(key, rule, alertSamples)
 }
 
 func (r *Runner) save() error {
-	// TODO: do a file switch only after the write is done.
-	f, err := os.OpenFile(*hostFile, os.O_WRONLY, 0)
+	// Write to a temp file and rename it over hostFile, so a reader (or
+	// a crash) never sees a partially-written config.
+	tmp := *hostFile + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return fmt.Errorf("save Open: %v", err)
 	}
-	defer f.Close()
 	r.Lock()
 	err = json.NewEncoder(f).Encode(r.Hosts)
 	r.Unlock()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
 	if err != nil {
-		return fmt.Errorf("loadRules json Encode: %v", err)
+		os.Remove(tmp)
+		return fmt.Errorf("save Encode: %v", err)
+	}
+	if err := os.Rename(tmp, *hostFile); err != nil {
+		return fmt.Errorf("save Rename: %v", err)
 	}
 	return nil
 }
@@ -174,15 +294,158 @@ func (r *Runner) NewHost(h *Host) error {
 	r.Hosts[h.Host] = h
 	r.Unlock()
 
+	r.coordinator.SetHosts(r.hostnames())
+	r.scheduleHost(h, *pollInterval)
 	r.save()
 	return nil
 }
 
+// ReloadResult summarizes what changed in a config reload.
+type ReloadResult struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Errored []string `json:"errored,omitempty"`
+}
+
+// Reload re-reads file and diffs it against the running host set,
+// starting/stopping only the probe goroutines for hosts that were
+// added, removed, or had any field changed. Restarting on any change
+// (not just Probes) matters because the probe goroutines close over the
+// *Host pointer at schedule time, so a stale pointer would otherwise
+// keep alerting/probing against the old config forever. Hosts that are
+// unaffected keep running (and keep their Store history) without
+// interruption.
+func (r *Runner) Reload(file string) (ReloadResult, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return ReloadResult{}, fmt.Errorf("Reload Stat: %v", err)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return ReloadResult{}, fmt.Errorf("Reload Open: %v", err)
+	}
+	defer f.Close()
+
+	var newHosts map[string]*Host
+	if err := json.NewDecoder(f).Decode(&newHosts); err != nil {
+		return ReloadResult{}, fmt.Errorf("Reload Decode: %v", err)
+	}
+
+	r.Lock()
+	oldHosts := r.Hosts
+	r.Hosts = newHosts
+	r.last = fi.ModTime()
+	r.Unlock()
+
+	var result ReloadResult
+	for name, oldHost := range oldHosts {
+		newHost, ok := newHosts[name]
+		if !ok {
+			result.Removed = append(result.Removed, name)
+			r.stopHost(name)
+			continue
+		}
+		if reflect.DeepEqual(oldHost, newHost) {
+			continue
+		}
+		r.stopHost(name)
+		if err := validateProbes(newHost); err != nil {
+			log.Printf("Reload: %v: %v", name, err)
+			result.Errored = append(result.Errored, name)
+			continue
+		}
+		r.scheduleHost(newHost, *pollInterval)
+	}
+	for name, newHost := range newHosts {
+		if _, ok := oldHosts[name]; ok {
+			continue
+		}
+		result.Added = append(result.Added, name)
+		if err := validateProbes(newHost); err != nil {
+			log.Printf("Reload: %v: %v", name, err)
+			result.Errored = append(result.Errored, name)
+			continue
+		}
+		r.scheduleHost(newHost, *pollInterval)
+	}
+	r.coordinator.SetHosts(r.hostnames())
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Strings(result.Errored)
+	return result, nil
+}
+
+// watchConfig reloads the host config whenever file's mtime advances
+// (checked every poll) or the process receives SIGHUP.
+func (r *Runner) watchConfig(file string, poll time.Duration) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sighup:
+			log.Println("watchConfig: SIGHUP received, reloading")
+		case <-ticker.C:
+			fi, err := os.Stat(file)
+			if err != nil {
+				log.Println("watchConfig Stat:", err)
+				continue
+			}
+			r.Lock()
+			stale := fi.ModTime().After(r.last)
+			r.Unlock()
+			if !stale {
+				continue
+			}
+		}
+		result, err := r.Reload(file)
+		if err != nil {
+			log.Println("watchConfig reload:", err)
+			continue
+		}
+		log.Printf("watchConfig reload: added %v removed %v errored %v", result.Added, result.Removed, result.Errored)
+	}
+}
+
+// hostnames returns every hostname currently monitored.
+func (r *Runner) hostnames() []string {
+	r.Lock()
+	defer r.Unlock()
+	names := make([]string, 0, len(r.Hosts))
+	for name := range r.Hosts {
+		names = append(names, name)
+	}
+	return names
+}
+
+func newStore() store.Store {
+	switch *storeKind {
+	case "sqlite":
+		s, err := store.NewSQLiteStore(*sqlitePath)
+		if err != nil {
+			log.Fatal("newStore: ", err)
+		}
+		if *retention > 0 {
+			s.StartRetention(time.Hour, *retention)
+		}
+		return s
+	default:
+		return store.NewMemStore()
+	}
+}
+
 func StartRunner(file string, poll time.Duration) *Runner {
 	r := new(Runner)
+	r.Store = newStore()
+	r.alertMgr = alerts.NewManager()
+	r.coordinator = cluster.NewCoordinator(*workerToken, *workerHeartbeat)
 	if err := r.loadRules(file); err != nil {
 		log.Println("StartRunner:", err)
 	}
+	r.coordinator.SetHosts(r.hostnames())
 
 	if len(r.Hosts) > *maxHosts {
 		log.Printf("Warning: the configuration file at '%v' contains more hosts than what is set in -maxHosts.", file)
@@ -190,27 +453,78 @@ func StartRunner(file string, poll time.Duration) *Runner {
 		log.Print("We will use the provided configuration and ignore the flag limit, so we will use more memory.")
 	}
 
-	tick := time.Tick(poll)
+	for _, h := range r.Hosts {
+		r.scheduleHost(h, poll)
+	}
 
 	go func() {
-		for _ = range tick {
-			errc := make(chan error)
-			for _, h := range r.Hosts {
-				go func(host *Host) {
-					errc <- r.Ping(host)
-				}(h)
-			}
-			for _ = range r.Hosts {
-				if err := <-errc; err != nil {
-					log.Println(err)
-				}
-			}
+		for range time.Tick(poll) {
 			r.save()
 		}
 	}()
+	go r.watchConfig(file, poll)
 	return r
 }
 
+// scheduleHost starts one goroutine per configured probe (or, for a
+// host with no Probes, one legacy HTTP probe against "/"), each ticking
+// at its own interval. The goroutines run until stopHost(h.Host) is
+// called, so a config reload can retire exactly the hosts that changed.
+func (r *Runner) scheduleHost(h *Host, defaultInterval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Lock()
+	if r.hostCancel == nil {
+		r.hostCancel = make(map[string]context.CancelFunc)
+	}
+	r.hostCancel[h.Host] = cancel
+	r.Unlock()
+
+	for _, spec := range h.probeSpecs() {
+		interval := spec.Interval
+		if interval == 0 {
+			interval = defaultInterval
+		}
+		go func(spec probe.ProbeSpec, interval time.Duration) {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := r.RunProbe(h, spec); err != nil {
+						log.Println(err)
+					}
+				}
+			}
+		}(spec, interval)
+	}
+}
+
+// stopHost cancels every probe goroutine scheduleHost started for host,
+// if any.
+func (r *Runner) stopHost(host string) {
+	r.Lock()
+	cancel, ok := r.hostCancel[host]
+	delete(r.hostCancel, host)
+	r.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// validateProbes builds (without running) every probe configured on
+// host, surfacing a bad ProbeSpec (e.g. an unknown type) before it's
+// scheduled.
+func validateProbes(h *Host) error {
+	for _, spec := range h.Probes {
+		if _, err := spec.Build(h.Host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *Runner) loadRules(file string) error {
 	fi, err := os.Stat(file)
 	if err != nil {
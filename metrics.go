@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/nictuku/pingmeplz/store"
+)
+
+// buildVersion is overwritten at build time with -ldflags, e.g.
+//   go build -ldflags "-X main.buildVersion=$(git describe)"
+var buildVersion = "dev"
+
+var processStartTime = time.Now()
+
+// latencyBuckets are the upper bounds, in seconds, of the
+// pingmeplz_probe_latency_seconds_bucket histogram.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogramLookback caps how many of a host's most recent samples feed
+// the latency histogram on each scrape, so a scrape stays cheap no
+// matter how much lifetime history the Store holds for that host.
+const histogramLookback = 1000
+
+// metricsHandler exposes probe results for every monitored host in
+// Prometheus text exposition format, so pingmeplz can be scraped directly
+// instead of only viewed through the /history chart.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	logreq(r)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	runner.Lock()
+	hosts := make([]*Host, 0, len(runner.Hosts))
+	for _, h := range runner.Hosts {
+		hosts = append(hosts, h)
+	}
+	runner.Unlock()
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+
+	// keys pairs every host with the Store key of each of its configured
+	// probes, so a host monitored by a non-default probe (e.g. TCP or
+	// ICMP) is scraped under that probe's own series instead of the bare
+	// hostname, which is only ever written by a root HTTP probe.
+	type probeKey struct {
+		host, probe, key string
+	}
+	var keys []probeKey
+	for _, h := range hosts {
+		for _, spec := range h.probeSpecs() {
+			keys = append(keys, probeKey{host: h.Host, probe: probeLabel(spec), key: sampleKey(h.Host, spec)})
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP pingmeplz_probe_success Whether the last probe against the host succeeded (1) or failed (0).")
+	fmt.Fprintln(w, "# TYPE pingmeplz_probe_success gauge")
+	for _, pk := range keys {
+		last, err := runner.Store.Latest(pk.key)
+		success := 0
+		if err == nil && last.Err == nil {
+			success = 1
+		}
+		fmt.Fprintf(w, "pingmeplz_probe_success{host=%q,probe=%q} %d\n", pk.host, pk.probe, success)
+	}
+
+	fmt.Fprintln(w, "# HELP pingmeplz_probe_latency_seconds Latency of the last probe against the host, in seconds.")
+	fmt.Fprintln(w, "# TYPE pingmeplz_probe_latency_seconds gauge")
+	for _, pk := range keys {
+		last, _ := runner.Store.Latest(pk.key)
+		fmt.Fprintf(w, "pingmeplz_probe_latency_seconds{host=%q,probe=%q} %v\n", pk.host, pk.probe, last.Latency.Seconds())
+	}
+
+	fmt.Fprintln(w, "# HELP pingmeplz_probe_last_status_code HTTP status code of the last probe against the host.")
+	fmt.Fprintln(w, "# TYPE pingmeplz_probe_last_status_code gauge")
+	for _, pk := range keys {
+		last, _ := runner.Store.Latest(pk.key)
+		fmt.Fprintf(w, "pingmeplz_probe_last_status_code{host=%q,probe=%q} %d\n", pk.host, pk.probe, last.StatusCode)
+	}
+
+	fmt.Fprintln(w, "# HELP pingmeplz_probe_latency_seconds_bucket Cumulative histogram of the most recent probe latencies held in the Store.")
+	fmt.Fprintln(w, "# TYPE pingmeplz_probe_latency_seconds_bucket histogram")
+	for _, pk := range keys {
+		samples, err := runner.Store.LastN(pk.key, histogramLookback)
+		if err != nil {
+			log.Println("metricsHandler LastN:", err)
+			continue
+		}
+		counts, sum, total := latencyHistogram(samples)
+		for i, bucket := range latencyBuckets {
+			fmt.Fprintf(w, "pingmeplz_probe_latency_seconds_bucket{host=%q,probe=%q,le=\"%g\"} %d\n", pk.host, pk.probe, bucket, counts[i])
+		}
+		fmt.Fprintf(w, "pingmeplz_probe_latency_seconds_bucket{host=%q,probe=%q,le=\"+Inf\"} %d\n", pk.host, pk.probe, total)
+		fmt.Fprintf(w, "pingmeplz_probe_latency_seconds_sum{host=%q,probe=%q} %v\n", pk.host, pk.probe, sum)
+		fmt.Fprintf(w, "pingmeplz_probe_latency_seconds_count{host=%q,probe=%q} %d\n", pk.host, pk.probe, total)
+	}
+
+	fmt.Fprintln(w, "# HELP pingmeplz_build_info Build information about the running pingmeplz binary. Always 1.")
+	fmt.Fprintln(w, "# TYPE pingmeplz_build_info gauge")
+	fmt.Fprintf(w, "pingmeplz_build_info{version=%q} 1\n", buildVersion)
+
+	fmt.Fprintln(w, "# HELP pingmeplz_process_start_time_seconds Unix timestamp of when the process started.")
+	fmt.Fprintln(w, "# TYPE pingmeplz_process_start_time_seconds gauge")
+	fmt.Fprintf(w, "pingmeplz_process_start_time_seconds %d\n", processStartTime.Unix())
+}
+
+// latencyHistogram computes cumulative bucket counts, the sum of
+// observed latencies and the total number of successful samples, by
+// scanning samples. It recomputes on every scrape rather than
+// maintaining running counters, since the Store (and not a separate
+// histogram) is the authoritative record of what happened.
+func latencyHistogram(samples []store.Sample) (counts []int64, sum float64, total int64) {
+	counts = make([]int64, len(latencyBuckets))
+	for _, s := range samples {
+		if s.Err != nil {
+			continue
+		}
+		seconds := s.Latency.Seconds()
+		sum += seconds
+		total++
+		for b, bucket := range latencyBuckets {
+			if seconds <= bucket {
+				counts[b]++
+			}
+		}
+	}
+	return counts, sum, total
+}
@@ -0,0 +1,41 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DNSProbe resolves Name and fails if the resolver returns no records.
+// When Resolver is set, lookups go against that server instead of the
+// system resolver.
+type DNSProbe struct {
+	Name     string
+	Resolver string // e.g. "8.8.8.8:53"
+	Timeout  time.Duration
+}
+
+func (p *DNSProbe) Run(ctx context.Context) (Result, error) {
+	resolver := net.DefaultResolver
+	if p.Resolver != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				d := net.Dialer{Timeout: p.Timeout}
+				return d.DialContext(ctx, network, p.Resolver)
+			},
+		}
+	}
+
+	start := time.Now()
+	addrs, err := resolver.LookupHost(ctx, p.Name)
+	latency := time.Since(start)
+	if err != nil {
+		return Result{Latency: latency}, fmt.Errorf("DNSProbe: %v", err)
+	}
+	if len(addrs) == 0 {
+		return Result{Latency: latency}, fmt.Errorf("DNSProbe: no records for %v", p.Name)
+	}
+	return Result{Latency: latency}, nil
+}
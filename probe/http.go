@@ -0,0 +1,88 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPProbe issues an HTTP request and checks the response status,
+// an optional header, and an optional substring in the body.
+type HTTPProbe struct {
+	URL          string
+	Method       string
+	ExpectStatus []int
+	ExpectBody   string
+	ExpectHeader string // "Name: value"
+	Timeout      time.Duration
+}
+
+func (p *HTTPProbe) Run(ctx context.Context) (Result, error) {
+	method := p.Method
+	if method == "" {
+		method = "GET"
+	}
+	req, err := http.NewRequest(method, p.URL, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("HTTPProbe: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	client := &http.Client{Timeout: p.Timeout}
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return Result{Latency: latency}, fmt.Errorf("HTTPProbe: %v", err)
+	}
+	defer resp.Body.Close()
+
+	result := Result{Latency: latency, StatusCode: resp.StatusCode}
+
+	if !p.statusExpected(resp.StatusCode) {
+		return result, fmt.Errorf("HTTPProbe: unexpected status %v", resp.Status)
+	}
+
+	if p.ExpectHeader != "" {
+		name, value := splitHeader(p.ExpectHeader)
+		if got := resp.Header.Get(name); got != value {
+			return result, fmt.Errorf("HTTPProbe: header %v = %q, want %q", name, got, value)
+		}
+	}
+
+	if p.ExpectBody != "" {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return result, fmt.Errorf("HTTPProbe: reading body: %v", err)
+		}
+		if !bytes.Contains(body, []byte(p.ExpectBody)) {
+			return result, fmt.Errorf("HTTPProbe: response body missing %q", p.ExpectBody)
+		}
+	}
+
+	return result, nil
+}
+
+func (p *HTTPProbe) statusExpected(got int) bool {
+	if len(p.ExpectStatus) == 0 {
+		return got == http.StatusOK
+	}
+	for _, want := range p.ExpectStatus {
+		if got == want {
+			return true
+		}
+	}
+	return false
+}
+
+func splitHeader(s string) (name, value string) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return strings.TrimSpace(parts[0]), ""
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
@@ -0,0 +1,75 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// ICMPProbe sends a single ICMP echo request and measures the
+// round-trip time. It listens on an unprivileged UDP socket
+// ("udp4"), which the OS answers for ICMP on platforms that support it,
+// so pingmeplz doesn't need CAP_NET_RAW.
+type ICMPProbe struct {
+	Host    string
+	Timeout time.Duration
+}
+
+func (p *ICMPProbe) Run(ctx context.Context) (Result, error) {
+	conn, err := icmp.ListenPacket("udp4", "0.0.0.0")
+	if err != nil {
+		return Result{}, fmt.Errorf("ICMPProbe: listen: %v", err)
+	}
+	defer conn.Close()
+
+	dst, err := net.ResolveIPAddr("ip4", p.Host)
+	if err != nil {
+		return Result{}, fmt.Errorf("ICMPProbe: resolve: %v", err)
+	}
+
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Code: 0,
+		Body: &icmp.Echo{
+			ID:   os.Getpid() & 0xffff,
+			Seq:  1,
+			Data: []byte("pingmeplz"),
+		},
+	}
+	wb, err := msg.Marshal(nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("ICMPProbe: marshal: %v", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(p.Timeout)
+	}
+	conn.SetDeadline(deadline)
+
+	start := time.Now()
+	if _, err := conn.WriteTo(wb, &net.UDPAddr{IP: dst.IP}); err != nil {
+		return Result{}, fmt.Errorf("ICMPProbe: write: %v", err)
+	}
+
+	rb := make([]byte, 1500)
+	n, _, err := conn.ReadFrom(rb)
+	latency := time.Since(start)
+	if err != nil {
+		return Result{Latency: latency}, fmt.Errorf("ICMPProbe: read: %v", err)
+	}
+
+	rm, err := icmp.ParseMessage(1, rb[:n]) // 1 == ICMP for IPv4
+	if err != nil {
+		return Result{Latency: latency}, fmt.Errorf("ICMPProbe: parse: %v", err)
+	}
+	if rm.Type != ipv4.ICMPTypeEchoReply {
+		return Result{Latency: latency}, fmt.Errorf("ICMPProbe: unexpected reply type %v", rm.Type)
+	}
+	return Result{Latency: latency}, nil
+}
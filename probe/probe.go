@@ -0,0 +1,102 @@
+// Package probe implements the individual checks pingmeplz can run
+// against a monitored host: plain HTTP, raw TCP, ICMP echo, DNS
+// resolution and TLS certificate expiry.
+package probe
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Result is what a single Probe run produced. For most probe types
+// Latency is how long the check took; TLSProbe repurposes it to report
+// days remaining until certificate expiry, as its naturally comparable
+// quantity.
+type Result struct {
+	Latency    time.Duration
+	StatusCode int
+}
+
+// Probe is a single check that can be run against a host.
+type Probe interface {
+	// Run executes the probe once, honoring ctx's deadline and
+	// cancellation. A non-nil error means the probe failed; Result is
+	// still populated with whatever was measured before the failure.
+	Run(ctx context.Context) (Result, error)
+}
+
+// ProbeSpec is the JSON-serializable configuration for a Probe, as
+// stored in a host's config entry.
+type ProbeSpec struct {
+	// Type selects the probe implementation: "http" (the default when
+	// empty), "tcp", "icmp", "dns" or "tls".
+	Type string `json:"type,omitempty"`
+
+	// Interval overrides how often this probe runs; zero uses the
+	// Runner's default poll interval.
+	Interval time.Duration `json:"interval,omitempty"`
+
+	// Timeout overrides the per-run timeout; zero uses the Runner's
+	// default read timeout.
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// HTTP
+	Path         string `json:"path,omitempty"`
+	Method       string `json:"method,omitempty"`
+	ExpectStatus []int  `json:"expectStatus,omitempty"`
+	ExpectBody   string `json:"expectBody,omitempty"`
+	ExpectHeader string `json:"expectHeader,omitempty"` // "Name: value"
+
+	// TCP
+	Port int `json:"port,omitempty"`
+
+	// DNS
+	Resolver string `json:"resolver,omitempty"` // e.g. "8.8.8.8:53"
+
+	// TLS
+	MinDaysToExpiry int `json:"minDaysToExpiry,omitempty"`
+}
+
+// Build constructs the concrete Probe spec describes, targeting host.
+func (spec ProbeSpec) Build(host string) (Probe, error) {
+	switch spec.Type {
+	case "", "http":
+		return &HTTPProbe{
+			URL:          "http://" + host + spec.pathOrRoot(),
+			Method:       spec.Method,
+			ExpectStatus: spec.ExpectStatus,
+			ExpectBody:   spec.ExpectBody,
+			ExpectHeader: spec.ExpectHeader,
+			Timeout:      spec.Timeout,
+		}, nil
+	case "tcp":
+		return &TCPProbe{
+			Addr:    fmt.Sprintf("%s:%d", host, spec.Port),
+			Timeout: spec.Timeout,
+		}, nil
+	case "icmp":
+		return &ICMPProbe{Host: host, Timeout: spec.Timeout}, nil
+	case "dns":
+		return &DNSProbe{Name: host, Resolver: spec.Resolver, Timeout: spec.Timeout}, nil
+	case "tls":
+		port := spec.Port
+		if port == 0 {
+			port = 443
+		}
+		return &TLSProbe{
+			Addr:            fmt.Sprintf("%s:%d", host, port),
+			MinDaysToExpiry: spec.MinDaysToExpiry,
+			Timeout:         spec.Timeout,
+		}, nil
+	default:
+		return nil, fmt.Errorf("probe: unknown probe type %q", spec.Type)
+	}
+}
+
+func (spec ProbeSpec) pathOrRoot() string {
+	if spec.Path == "" {
+		return "/"
+	}
+	return spec.Path
+}
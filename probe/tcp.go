@@ -0,0 +1,27 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// TCPProbe dials Addr ("host:port") and reports whether the connection
+// succeeds within Timeout.
+type TCPProbe struct {
+	Addr    string
+	Timeout time.Duration
+}
+
+func (p *TCPProbe) Run(ctx context.Context) (Result, error) {
+	d := net.Dialer{Timeout: p.Timeout}
+	start := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", p.Addr)
+	latency := time.Since(start)
+	if err != nil {
+		return Result{Latency: latency}, fmt.Errorf("TCPProbe: %v", err)
+	}
+	conn.Close()
+	return Result{Latency: latency}, nil
+}
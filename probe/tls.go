@@ -0,0 +1,48 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+)
+
+// TLSProbe connects to Addr ("host:port") and reports the number of
+// days until the presented certificate expires, as Result.Latency. The
+// probe fails once that drops below MinDaysToExpiry.
+type TLSProbe struct {
+	Addr            string
+	MinDaysToExpiry int
+	Timeout         time.Duration
+}
+
+func (p *TLSProbe) Run(ctx context.Context) (Result, error) {
+	d := net.Dialer{Timeout: p.Timeout}
+	rawConn, err := d.DialContext(ctx, "tcp", p.Addr)
+	if err != nil {
+		return Result{}, fmt.Errorf("TLSProbe: %v", err)
+	}
+	defer rawConn.Close()
+
+	serverName, _, err := net.SplitHostPort(p.Addr)
+	if err != nil {
+		return Result{}, fmt.Errorf("TLSProbe: %v", err)
+	}
+	conn := tls.Client(rawConn, &tls.Config{ServerName: serverName})
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return Result{}, fmt.Errorf("TLSProbe: %v", err)
+	}
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return Result{}, fmt.Errorf("TLSProbe: no certificate presented by %v", p.Addr)
+	}
+
+	daysLeft := time.Until(certs[0].NotAfter) / (24 * time.Hour)
+	result := Result{Latency: daysLeft * 24 * time.Hour}
+	if int(daysLeft) < p.MinDaysToExpiry {
+		return result, fmt.Errorf("TLSProbe: certificate for %v expires in %d days", p.Addr, daysLeft)
+	}
+	return result, nil
+}
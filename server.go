@@ -3,12 +3,14 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
+	"strings"
 )
 
 func logreq(r *http.Request) {
@@ -158,57 +160,78 @@ var welcomeTmpl = template.Must(template.New("").Parse(`
 
 func newhost(w http.ResponseWriter, r *http.Request) {
 	logreq(r)
-	// Check required form arams.
-	for _, v := range []string{"host", "email"} {
-		if fv := r.FormValue(v); fv == "" {
-			http.Error(w, v+" not specified or invalid", http.StatusBadRequest)
+
+	var host *Host
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		host = new(Host)
+		if err := json.NewDecoder(r.Body).Decode(host); err != nil {
+			http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if host.Host == "" {
+			http.Error(w, "host not specified or invalid", http.StatusBadRequest)
 			return
 		}
+	} else {
+		// Check required form params.
+		for _, v := range []string{"host", "email"} {
+			if fv := r.FormValue(v); fv == "" {
+				http.Error(w, v+" not specified or invalid", http.StatusBadRequest)
+				return
+			}
+		}
+		host = &Host{Host: r.FormValue("host"), Email: r.FormValue("email")}
+		if specs := r.FormValue("probes"); specs != "" {
+			if err := json.Unmarshal([]byte(specs), &host.Probes); err != nil {
+				http.Error(w, "invalid probes: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
 	}
-	host := &Host{Host: r.FormValue("host"), Email: r.FormValue("email")}
 
+	if err := verifyOwnership(host.Host); err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Prerequisites are good, now add it to the config.
+	if err := runner.NewHost(host); err != nil {
+		log.Printf("newhost error: %v", err)
+		http.Error(w, "Could not include new host: "+host.Host, http.StatusInternalServerError)
+		return
+	}
+
+	s := fmt.Sprintf("Added host: %v", host.Host)
+	log.Println(s)
+	fmt.Fprint(w, s)
+}
+
+// verifyOwnership checks that hostname resolves and that its owner
+// added "pingmeplz.com" to robots.txt, as proof that they authorized
+// this monitoring.
+func verifyOwnership(hostname string) error {
 	// Ensure this is a host address and not something more dodgy
 	// such as fatdownloads.com/supershugefile.zip
-	if ips, err := net.LookupIP(host.Host); err != nil || len(ips) == 0 {
-		s := fmt.Sprintf("Host could not be resolved")
-		log.Println(s)
-		http.Error(w, s, http.StatusBadRequest)
-		return
+	if ips, err := net.LookupIP(hostname); err != nil || len(ips) == 0 {
+		return fmt.Errorf("Host could not be resolved")
 	}
 	// TODO(nictuku): Don't download big files and blacklist a host that asks me to.
 
-	// Check that they added "pingmeplz.com" to robots.txt, as a way 
-	// to say they authorized this monitoring.
-	u := fmt.Sprintf("http://%s/robots.txt", host.Host)
+	u := fmt.Sprintf("http://%s/robots.txt", hostname)
 	resp, err := getWithTimeout(u, *readTimeout)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 	if err != nil || resp == nil || resp.StatusCode != 200 {
-		s := fmt.Sprintf("newhost GET error: %v", err)
-		log.Println(s)
-		http.Error(w, s, http.StatusInternalServerError)
-		return
+		return fmt.Errorf("newhost GET error: %v", err)
 	}
 
 	bs, err := ioutil.ReadAll(resp.Body)
-	if !bytes.Contains(bs, []byte("pingmeplz.com")) {
-		s := fmt.Sprintf("Please add pingmeplz.com somewhere in http://%v/robots.txt "+
-			"(for example, in a comment line), as a proof that you own this domain and want it to be monitored", host.Host)
-		log.Print(s)
-		http.Error(w, s, http.StatusBadRequest)
-		return
+	if err != nil || !bytes.Contains(bs, []byte("pingmeplz.com")) {
+		return fmt.Errorf("Please add pingmeplz.com somewhere in http://%v/robots.txt "+
+			"(for example, in a comment line), as a proof that you own this domain and want it to be monitored", hostname)
 	}
-	log.Println("found the string pingmeplz.com in the robots.txt file for", host.Host)
-
-	// Prerequisites are good, now add it to the config.
-	if err := runner.NewHost(host); err != nil {
-		log.Printf("newhost error: %v", err)
-		http.Error(w, "Could not include new host: "+host.Host, http.StatusInternalServerError)
-		return
-	}
-
-	s := fmt.Sprintf("Added host: %v", host.Host)
-	log.Println(s)
-	fmt.Fprint(w, s)
+	log.Println("found the string pingmeplz.com in the robots.txt file for", hostname)
+	return nil
 }
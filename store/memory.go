@@ -0,0 +1,112 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BufSize caps how many samples MemStore keeps per host. This is the
+// primary driver of MemStore's memory usage.
+const BufSize = 10080 // 7d worth of 1m frequency collections.
+
+type ring struct {
+	mu      sync.Mutex
+	samples [BufSize]Sample
+	pos     int
+	full    bool
+}
+
+// MemStore is the original fixed-size in-memory ring buffer Store:
+// cheap and simple, but it loses everything on restart and pins
+// BufSize*len(hosts) samples in RAM regardless of how much history is
+// actually needed.
+type MemStore struct {
+	mu    sync.Mutex
+	hosts map[string]*ring
+}
+
+// NewMemStore returns an empty MemStore ready to use.
+func NewMemStore() *MemStore {
+	return &MemStore{hosts: make(map[string]*ring)}
+}
+
+func (m *MemStore) ringFor(host string) *ring {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rg, ok := m.hosts[host]
+	if !ok {
+		rg = &ring{}
+		m.hosts[host] = rg
+	}
+	return rg
+}
+
+func (m *MemStore) Append(host string, sample Sample) error {
+	rg := m.ringFor(host)
+	rg.mu.Lock()
+	rg.samples[rg.pos] = sample
+	rg.pos = (rg.pos + 1) % BufSize
+	if rg.pos == 0 {
+		rg.full = true
+	}
+	rg.mu.Unlock()
+	return nil
+}
+
+func (m *MemStore) Range(host string, from, to time.Time) ([]Sample, error) {
+	rg := m.ringFor(host)
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	n := rg.pos
+	if rg.full {
+		n = BufSize
+	}
+	start := 0
+	if rg.full {
+		start = rg.pos
+	}
+
+	out := make([]Sample, 0, n)
+	for i := 0; i < n; i++ {
+		s := rg.samples[(start+i)%BufSize]
+		if s.Time.Before(from) || s.Time.After(to) {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (m *MemStore) LastN(host string, n int) ([]Sample, error) {
+	rg := m.ringFor(host)
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	avail := rg.pos
+	if rg.full {
+		avail = BufSize
+	}
+	if n > avail {
+		n = avail
+	}
+	out := make([]Sample, n)
+	for i := 0; i < n; i++ {
+		idx := (rg.pos - n + i + BufSize) % BufSize
+		out[i] = rg.samples[idx]
+	}
+	return out, nil
+}
+
+func (m *MemStore) Latest(host string) (Sample, error) {
+	rg := m.ringFor(host)
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	if rg.pos == 0 && !rg.full {
+		return Sample{}, fmt.Errorf("no samples recorded for %v", host)
+	}
+	idx := (rg.pos - 1 + BufSize) % BufSize
+	return rg.samples[idx], nil
+}
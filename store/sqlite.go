@@ -0,0 +1,146 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore persists samples to a SQLite database (via the pure-Go
+// modernc.org/sqlite driver) so probe history survives restarts and can
+// grow unbounded in duration without exploding RAM the way MemStore
+// does.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if necessary) the SQLite database at
+// path and ensures its schema exists.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("NewSQLiteStore Open: %v", err)
+	}
+	const schema = `
+CREATE TABLE IF NOT EXISTS samples (
+	host TEXT NOT NULL,
+	ts INTEGER NOT NULL,
+	latency_ns INTEGER NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	err TEXT
+);
+CREATE INDEX IF NOT EXISTS samples_host_ts ON samples (host, ts);
+`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("NewSQLiteStore schema: %v", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Append(host string, sample Sample) error {
+	var errStr interface{}
+	if sample.Err != nil {
+		errStr = sample.Err.Error()
+	}
+	_, err := s.db.Exec(
+		`INSERT INTO samples (host, ts, latency_ns, status_code, err) VALUES (?, ?, ?, ?, ?)`,
+		host, sample.Time.UnixNano(), int64(sample.Latency), sample.StatusCode, errStr)
+	if err != nil {
+		return fmt.Errorf("SQLiteStore Append: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Range(host string, from, to time.Time) ([]Sample, error) {
+	rows, err := s.db.Query(
+		`SELECT ts, latency_ns, status_code, err FROM samples WHERE host = ? AND ts >= ? AND ts <= ? ORDER BY ts ASC`,
+		host, from.UnixNano(), to.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("SQLiteStore Range: %v", err)
+	}
+	defer rows.Close()
+
+	var out []Sample
+	for rows.Next() {
+		sample, err := scanSample(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("SQLiteStore Range scan: %v", err)
+		}
+		out = append(out, sample)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLiteStore) LastN(host string, n int) ([]Sample, error) {
+	rows, err := s.db.Query(
+		`SELECT ts, latency_ns, status_code, err FROM samples WHERE host = ? ORDER BY ts DESC LIMIT ?`,
+		host, n)
+	if err != nil {
+		return nil, fmt.Errorf("SQLiteStore LastN: %v", err)
+	}
+	defer rows.Close()
+
+	var out []Sample
+	for rows.Next() {
+		sample, err := scanSample(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("SQLiteStore LastN scan: %v", err)
+		}
+		out = append(out, sample)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, nil
+}
+
+func (s *SQLiteStore) Latest(host string) (Sample, error) {
+	row := s.db.QueryRow(
+		`SELECT ts, latency_ns, status_code, err FROM samples WHERE host = ? ORDER BY ts DESC LIMIT 1`, host)
+	sample, err := scanSample(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Sample{}, fmt.Errorf("no samples recorded for %v", host)
+	}
+	if err != nil {
+		return Sample{}, fmt.Errorf("SQLiteStore Latest: %v", err)
+	}
+	return sample, nil
+}
+
+func scanSample(scan func(dest ...interface{}) error) (Sample, error) {
+	var ts, latencyNs int64
+	var statusCode int
+	var errStr sql.NullString
+	if err := scan(&ts, &latencyNs, &statusCode, &errStr); err != nil {
+		return Sample{}, err
+	}
+	sample := Sample{
+		Time:       time.Unix(0, ts),
+		Latency:    time.Duration(latencyNs),
+		StatusCode: statusCode,
+	}
+	if errStr.Valid {
+		sample.Err = errors.New(errStr.String)
+	}
+	return sample, nil
+}
+
+// StartRetention spawns a goroutine that, every interval, deletes
+// samples older than retention. It runs until the process exits.
+func (s *SQLiteStore) StartRetention(interval, retention time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			cutoff := time.Now().Add(-retention).UnixNano()
+			if _, err := s.db.Exec(`DELETE FROM samples WHERE ts < ?`, cutoff); err != nil {
+				fmt.Println("SQLiteStore retention delete:", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,34 @@
+// Package store persists probe samples for pingmeplz's monitored hosts.
+// Store is implemented by an in-memory ring (MemStore, the original
+// behavior) and by a SQLite-backed store (SQLiteStore) for history that
+// survives restarts and isn't bounded by RAM.
+package store
+
+import "time"
+
+// Sample is one probe result recorded for a host.
+type Sample struct {
+	Time       time.Time
+	Latency    time.Duration
+	Err        error
+	StatusCode int
+}
+
+// Store persists and retrieves probe samples for hosts.
+type Store interface {
+	// Append records a new sample for host.
+	Append(host string, sample Sample) error
+
+	// Range returns every sample recorded for host with a timestamp in
+	// [from, to], oldest first.
+	Range(host string, from, to time.Time) ([]Sample, error)
+
+	// LastN returns the most recent n samples recorded for host, oldest
+	// first. It's used where only a bounded recent window is needed
+	// (alerting, scrape-time histograms), so callers aren't forced to
+	// pull a host's entire lifetime history on every tick.
+	LastN(host string, n int) ([]Sample, error)
+
+	// Latest returns the most recently appended sample for host.
+	Latest(host string) (Sample, error)
+}
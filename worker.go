@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/nictuku/pingmeplz/cluster"
+	"github.com/nictuku/pingmeplz/probe"
+)
+
+// runWorker runs pingmeplz as a collaborative agent: it registers
+// against coordinatorURL and polls for its host assignments, both
+// authenticated with token, probes each assigned host with a plain
+// HTTP check and reports results back signed with token. It never
+// serves the web UI.
+func runWorker(coordinatorURL, token string, poll time.Duration) {
+	id, err := registerWithCoordinator(coordinatorURL, token)
+	if err != nil {
+		log.Fatal("runWorker: ", err)
+	}
+	log.Printf("registered with coordinator %v as %v", coordinatorURL, id)
+
+	for range time.Tick(poll) {
+		assignments, err := pollCoordinator(coordinatorURL, token, id)
+		if err != nil {
+			log.Println("runWorker poll:", err)
+			continue
+		}
+		for _, a := range assignments {
+			go probeAndReport(coordinatorURL, token, id, a.Host)
+		}
+	}
+}
+
+func registerWithCoordinator(coordinatorURL, token string) (cluster.WorkerID, error) {
+	req, err := http.NewRequest(http.MethodPost, coordinatorURL+"/api/v1/worker/register", nil)
+	if err != nil {
+		return "", fmt.Errorf("register: %v", err)
+	}
+	req.Header.Set(workerTokenHeader, token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("register: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("register: %v", resp.Status)
+	}
+	var r cluster.RegisterResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", fmt.Errorf("register decode: %v", err)
+	}
+	return r.WorkerID, nil
+}
+
+func pollCoordinator(coordinatorURL, token string, id cluster.WorkerID) ([]cluster.Assignment, error) {
+	u := fmt.Sprintf("%s/api/v1/worker/poll?workerId=%s", coordinatorURL, id)
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("poll: %v", err)
+	}
+	req.Header.Set(workerTokenHeader, token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("poll: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("poll: %v", resp.Status)
+	}
+	var r cluster.PollResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("poll decode: %v", err)
+	}
+	return r.Assignments, nil
+}
+
+func probeAndReport(coordinatorURL, token string, id cluster.WorkerID, host string) {
+	spec := probe.ProbeSpec{Type: "http", Path: "/"}
+	p, err := spec.Build(host)
+	if err != nil {
+		log.Println("probeAndReport Build:", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *readTimeout)
+	defer cancel()
+	result, runErr := p.Run(ctx)
+
+	req := cluster.ReportRequest{
+		WorkerID:   id,
+		Host:       host,
+		Time:       time.Now(),
+		LatencyNs:  int64(result.Latency),
+		StatusCode: result.StatusCode,
+	}
+	if runErr != nil {
+		req.Err = runErr.Error()
+	}
+	req.Signature = cluster.Sign(token, req.Payload())
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		log.Println("probeAndReport marshal:", err)
+		return
+	}
+	resp, err := http.Post(coordinatorURL+"/api/v1/worker/report", "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Println("probeAndReport report:", err)
+		return
+	}
+	resp.Body.Close()
+}
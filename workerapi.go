@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/nictuku/pingmeplz/cluster"
+	"github.com/nictuku/pingmeplz/store"
+)
+
+// workerTokenHeader carries the shared token on register/poll requests,
+// which (unlike report) have no signed payload to authenticate against.
+const workerTokenHeader = "X-PingMePlz-Token"
+
+// workerRegister handles POST /api/v1/worker/register: a new worker
+// agent enrolls and gets back a WorkerID to use for poll/report.
+func workerRegister(w http.ResponseWriter, r *http.Request) {
+	logreq(r)
+	if !runner.coordinator.CheckToken(r.Header.Get(workerTokenHeader)) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	id := runner.coordinator.Register()
+	json.NewEncoder(w).Encode(cluster.RegisterResponse{WorkerID: id})
+}
+
+// workerPoll handles GET /api/v1/worker/poll?workerId=...: a registered
+// worker asks which hosts it's currently responsible for.
+func workerPoll(w http.ResponseWriter, r *http.Request) {
+	logreq(r)
+	if !runner.coordinator.CheckToken(r.Header.Get(workerTokenHeader)) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	id := cluster.WorkerID(r.FormValue("workerId"))
+	if id == "" {
+		http.Error(w, "workerId not specified", http.StatusBadRequest)
+		return
+	}
+	assignments, err := runner.coordinator.Assignments(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(cluster.PollResponse{Assignments: assignments})
+}
+
+// workerReport handles POST /api/v1/worker/report: a worker pushes back
+// one probe result, signed with the shared token. Results are recorded
+// as their own per-vantage-point series in the Store, keyed
+// "<host>@<workerId>", alongside the coordinator's own local series.
+func workerReport(w http.ResponseWriter, r *http.Request) {
+	logreq(r)
+	var req cluster.ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid report: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !runner.coordinator.Verify(req.Payload(), req.Signature) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	key := vantageKey(req.Host, req.WorkerID)
+	sample := store.Sample{
+		Time:       req.Time,
+		Latency:    time.Duration(req.LatencyNs),
+		StatusCode: req.StatusCode,
+	}
+	if req.Err != "" {
+		sample.Err = errors.New(req.Err)
+	}
+	if err := runner.Store.Append(key, sample); err != nil {
+		log.Println("workerReport Store Append:", err)
+		http.Error(w, "could not record report", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// vantageKey is the Store key a worker's results for host are recorded
+// under: its own series, separate from the coordinator's local probes.
+func vantageKey(host string, worker cluster.WorkerID) string {
+	return host + "@" + string(worker)
+}